@@ -0,0 +1,156 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ctxKey is the unexported type used to store a *ctxLogger on a
+// context.Context, so it can't collide with keys set by other packages
+type ctxKey struct{}
+
+// field is a single key/value pair attached to a context via With
+type field struct {
+	key   string
+	value interface{}
+}
+
+// ctxLogger carries the fields accumulated by With for a single context chain
+type ctxLogger struct {
+	fields []field
+}
+
+// With returns a copy of ctx which carries the given fields, in addition to
+// any fields already attached by an earlier call to With on an ancestor
+// context. fields must alternate key, value, key, value, ... with string
+// keys; malformed pairs are dropped
+func With(ctx context.Context, fields ...interface{}) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	var existing []field
+	if l, ok := ctx.Value(ctxKey{}).(*ctxLogger); ok {
+		existing = l.fields
+	}
+
+	nl := &ctxLogger{fields: make([]field, len(existing), len(existing)+len(fields)/2)}
+	copy(nl.fields, existing)
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok {
+			continue
+		}
+		nl.fields = append(nl.fields, field{key: key, value: fields[i+1]})
+	}
+
+	return context.WithValue(ctx, ctxKey{}, nl)
+}
+
+// ContextLogger logs messages annotated with the fields attached to a
+// context via With. Obtain one with Ctx
+type ContextLogger struct {
+	fields []field
+}
+
+// Ctx returns a ContextLogger which appends the fields attached to ctx via
+// With to every message it logs. If ctx has no fields attached, Ctx returns a
+// zero-value ContextLogger which behaves identically to, and allocates no
+// more than, the package-level functions
+func Ctx(ctx context.Context) ContextLogger {
+	l, ok := ctx.Value(ctxKey{}).(*ctxLogger)
+	if !ok {
+		return ContextLogger{}
+	}
+	return ContextLogger{fields: l.fields}
+}
+
+// suffix renders the attached fields as key=value pairs. It returns an empty
+// string, without allocating, when there are no fields
+func (c ContextLogger) suffix() string {
+	if len(c.fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, f := range c.fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s=%v", f.key, f.value)
+	}
+	return b.String()
+}
+
+// Trace logs a tracing message, annotated with the context's fields
+func (c ContextLogger) Trace(msgFmt string, v ...interface{}) {
+	if logLevel < LevelTrace {
+		return
+	}
+	print(LevelTrace, "95", "TRC", msgFmt, c.suffix(), v...)
+}
+
+// Debug logs a debugging message, annotated with the context's fields
+func (c ContextLogger) Debug(msgFmt string, v ...interface{}) {
+	if logLevel < LevelDebug {
+		return
+	}
+	print(LevelDebug, "96", "DBG", msgFmt, c.suffix(), v...)
+}
+
+// Info logs an informative message, annotated with the context's fields
+func (c ContextLogger) Info(msgFmt string, v ...interface{}) {
+	if logLevel < LevelInfo {
+		return
+	}
+	print(LevelInfo, "92", "INF", msgFmt, c.suffix(), v...)
+}
+
+// Warn logs a warning message, annotated with the context's fields
+func (c ContextLogger) Warn(msgFmt string, v ...interface{}) {
+	if logLevel < LevelWarning {
+		return
+	}
+	print(LevelWarning, "93", "WRN", msgFmt, c.suffix(), v...)
+}
+
+// Error logs an error message, annotated with the context's fields, and
+// prints a filtered execution stack afterwards
+func (c ContextLogger) Error(msgFmt string, v ...interface{}) {
+	if logLevel < LevelError {
+		return
+	}
+	print(LevelError, "91", "ERR", msgFmt, c.suffix(), v...)
+	logStack(LevelError, true)
+}
+
+// Middleware wraps an http.Handler, seeding each request's context with a
+// generated request ID and logging the request's start and end at Trace
+// level. Downstream handlers can retrieve the request ID, and attach further
+// fields of their own, via Ctx(r.Context())
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := With(r.Context(), "request_id", newRequestID())
+		l := Ctx(ctx)
+
+		start := time.Now()
+		l.Trace("Started %s %s", r.Method, r.URL.Path)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		l.Trace("Finished %s %s in %s", r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+// newRequestID generates a random hex-encoded request ID for Middleware
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
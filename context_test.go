@@ -0,0 +1,88 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCtxZeroValueIsUsable(t *testing.T) {
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	Ctx(context.Background()).Info("hello")
+
+	if len(c.lines) != 1 {
+		t.Fatalf("expected exactly one line to be logged, got %v", c.lines)
+	}
+	if strings.Contains(c.lines[0], "=") {
+		t.Fatalf("expected no fields to be appended for a bare context, got %q", c.lines[0])
+	}
+}
+
+func TestCtxZeroValueAllocatesNothing(t *testing.T) {
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l := Ctx(ctx)
+		_ = l.suffix()
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations when no fields are attached, got %v", allocs)
+	}
+}
+
+func TestWithAccumulatesFields(t *testing.T) {
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	ctx := With(context.Background(), "request_id", "abc123")
+	ctx = With(ctx, "user_id", 42)
+
+	Ctx(ctx).Info("hello")
+
+	if len(c.lines) != 1 {
+		t.Fatalf("expected exactly one line to be logged, got %v", c.lines)
+	}
+	line := c.lines[0]
+	if !strings.Contains(line, "request_id=abc123") {
+		t.Fatalf("expected the first With call's field to be present, got %q", line)
+	}
+	if !strings.Contains(line, "user_id=42") {
+		t.Fatalf("expected the second With call's field to be present, got %q", line)
+	}
+}
+
+func TestWithDoesNotMutateParentContext(t *testing.T) {
+	parent := With(context.Background(), "a", 1)
+	child := With(parent, "b", 2)
+
+	if len(Ctx(parent).fields) != 1 {
+		t.Fatal("expected the parent context's fields to be unaffected by the child's With call")
+	}
+	if len(Ctx(child).fields) != 2 {
+		t.Fatal("expected the child context to carry both fields")
+	}
+}
+
+func TestMiddlewareSeedsRequestID(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, f := range Ctx(r.Context()).fields {
+			if f.key == "request_id" {
+				seen, _ = f.value.(string)
+			}
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen == "" {
+		t.Fatal("expected Middleware to seed a non-empty request_id")
+	}
+}
@@ -1,19 +1,19 @@
 // This is a simple logging package which can be used anywhere without any
-// configuration. This package only logs to stdout and is supposed to be used in
-// conjunction with an external system logger, like systemd-journal.
+// configuration. By default it only logs to stdout and is supposed to be used
+// in conjunction with an external system logger, like systemd-journal.
+// Additional destinations can be registered with AddSink.
 
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"runtime"
-	"runtime/debug"
+	"time"
 )
 
-var logger *log.Logger
-
 // Log level, higher number is more verbosity
 const (
 	// LevelTrace is used for printing verbose network communications
@@ -51,9 +51,12 @@ var defaultLevel = LevelDebug
 // on the log level of the logged message. Defaults to false
 var Colours = false
 
-func init() {
-	logger = log.New(os.Stdout, "", log.LUTC)
-}
+// JSON controls if the log package should print its output as JSON objects
+// instead of the default human-readable text. This is useful when shipping
+// logs to an ingestion system which expects structured records, e.g. journald
+// with structured forwarding enabled. Colours is ignored when JSON is on.
+// Defaults to false
+var JSON = false
 
 // SetLogLevel set the logging verbosity. 0 is lowest (log nothing at all), 5 is
 // highest (log all debug and trace messages)
@@ -70,7 +73,9 @@ func SetLogLevel(level int) {
 // logger interface into this log package.
 //
 // An example of this is the very verbose http logger which tends to spam logs
-// with messages which can otherwise not be silenced.
+// with messages which can otherwise not be silenced. See also SetRateLimit
+// and SetDedupWindow, which throttle and deduplicate spammy messages without
+// having to demote their entire level.
 func SetDefaultLevel(level int) {
 	if level < LevelNone || level > LevelDebug {
 		Error("Invalid log level %v", level)
@@ -84,7 +89,7 @@ func Trace(msgFmt string, v ...interface{}) {
 	if logLevel < LevelTrace {
 		return
 	}
-	print("95", "TRC", msgFmt, v...)
+	print(LevelTrace, "95", "TRC", msgFmt, "", v...)
 }
 
 // Debug logs a debugging message
@@ -92,7 +97,7 @@ func Debug(msgFmt string, v ...interface{}) {
 	if logLevel < LevelDebug {
 		return
 	}
-	print("96", "DBG", msgFmt, v...)
+	print(LevelDebug, "96", "DBG", msgFmt, "", v...)
 }
 
 // Info logs an informative message
@@ -100,7 +105,7 @@ func Info(msgFmt string, v ...interface{}) {
 	if logLevel < LevelInfo {
 		return
 	}
-	print("92", "INF", msgFmt, v...)
+	print(LevelInfo, "92", "INF", msgFmt, "", v...)
 }
 
 // Warn logs a warning message
@@ -108,16 +113,35 @@ func Warn(msgFmt string, v ...interface{}) {
 	if logLevel < LevelWarning {
 		return
 	}
-	print("93", "WRN", msgFmt, v...)
+	print(LevelWarning, "93", "WRN", msgFmt, "", v...)
 }
 
-// Error logs an error message, and prints an execution stack afterwards
+// Error logs an error message, and prints a filtered execution stack
+// afterwards. The number of frames printed is controlled by SetStackDepth
 func Error(msgFmt string, v ...interface{}) {
 	if logLevel < LevelError {
 		return
 	}
-	print("91", "ERR", msgFmt, v...)
-	debug.PrintStack()
+	print(LevelError, "91", "ERR", msgFmt, "", v...)
+	logStack(LevelError, true)
+}
+
+// Fatal logs msgFmt at Error level, the same way Error does, and then
+// terminates the program with exit code 1 after flushing all sinks. Unlike
+// Error, Fatal doesn't call print/logStack through Error: doing so would add
+// an extra stack frame, which would throw off the caller location print
+// derives from runtime.Caller and the depth logStack's own trace starts at.
+// Fatal's message and stack trace also bypass deduplication and rate
+// limiting, so a process that's about to exit always gets its final message
+// out instead of risking it being silently suppressed
+func Fatal(msgFmt string, v ...interface{}) {
+	if logLevel >= LevelError {
+		_, fn, line, _ := runtime.Caller(1)
+		printAt(LevelError, "91", "ERR", fn, line, msgFmt, "", nil, false, false, v...)
+		logStack(LevelError, false)
+	}
+	flushSinks()
+	os.Exit(1)
 }
 
 type writer int
@@ -143,10 +167,75 @@ func (writer) Write(p []byte) (n int, err error) {
 // packages to log to the Default log level
 var Logger = log.New(writer(0), "", 0)
 
-func print(colour string, lvl string, msgFmt string, v ...interface{}) {
+// print formats and dispatches a log message. suffix, if non-empty, is
+// appended to the message after formatting; it is used to append context
+// fields attached via With without requiring every caller to thread them
+// through msgFmt. The source file and line are taken from the caller of the
+// Trace/Debug/Info/Warn/Error wrapper two frames up
+func print(level int, colour string, lvl string, msgFmt string, suffix string, v ...interface{}) {
 	// Get the file name and line number
 	_, fn, line, _ := runtime.Caller(2)
+	printAt(level, colour, lvl, fn, line, msgFmt, suffix, nil, true, true, v...)
+}
+
+// printAt is like print, but takes the source file and line explicitly
+// instead of deriving them from the call stack, and lets the caller opt out
+// of deduplication and/or rate limiting. logStack uses this to report each
+// stack frame's own location instead of the location of the logStack call
+// site, and exempts the frames it prints from the rate limiter so a burst of
+// unrelated messages can't truncate a stack trace mid-frame. Fatal exempts
+// its message from both, so a process that's about to exit always gets its
+// final message out instead of risking it being silently suppressed. fields,
+// if non-nil, is emitted as structured key/value pairs in JSON mode instead
+// of being folded into the message text; it has no effect on the
+// human-readable format, which already gets its key=value pairs via suffix
+func printAt(level int, colour string, lvl string, fn string, line int, msgFmt string, suffix string, fields map[string]string, deduped bool, rateLimited bool, v ...interface{}) {
+	// If variadic arguments were passed we expand them with Sprintf, else we
+	// just use the message as-is
+	var message string
+	if len(v) == 0 {
+		message = msgFmt
+	} else {
+		message = fmt.Sprintf(msgFmt, v...)
+	}
 
+	// displayMessage is what the human-readable format and the dedup key use;
+	// it always carries the suffix, regardless of whether fields also carries
+	// the same data structured for JSON
+	displayMessage := message
+	if suffix != "" {
+		displayMessage += " " + suffix
+	}
+
+	// Dedup runs first so suppressed spam never touches the rate limiter's
+	// token budget and starves unrelated messages
+	if deduped && !dedupAllow(level, colour, lvl, fn, line, displayMessage) {
+		return
+	}
+	if rateLimited && !rateLimitAllow() {
+		return
+	}
+
+	var formatted string
+	if JSON {
+		// When fields is set the caller wants its key/value pairs emitted as
+		// real JSON keys, so the JSON message stays plain instead of also
+		// carrying them as unstructured key=value text
+		if fields != nil {
+			formatted = formatJSON(lvl, fn, line, message, fields)
+		} else {
+			formatted = formatJSON(lvl, fn, line, displayMessage, nil)
+		}
+	} else {
+		formatted = formatText(colour, lvl, fn, line, displayMessage)
+	}
+
+	writeSinks(level, fn, line, formatted)
+}
+
+// formatText renders a log line in the default human-readable format: the
+// log level, then the source file name, line number and the message
+func formatText(colour, lvl, fn string, line int, message string) string {
 	// Maximum length of the file path which is printed
 	var cutoff = 30
 	if len(fn) < cutoff {
@@ -158,21 +247,41 @@ func print(colour string, lvl string, msgFmt string, v ...interface{}) {
 		lvl = "\x1b[1m\x1b[" + colour + "m" + lvl + "\x1b[0m"
 	}
 
-	// Format the message to print. First the log level, then the source file
-	// name, line number and the message
-	msg := fmt.Sprintf(
+	return fmt.Sprintf(
 		"[%s] %30s:%-3d %s",
 		lvl,
 		"…"+string(fn[len(fn)-cutoff:]),
 		line,
-		msgFmt,
+		message,
 	)
+}
 
-	// If variadic arguments were passed we parse them with Printf, else we just
-	// print the message normally
-	if len(v) == 0 {
-		logger.Println(msg)
-	} else {
-		logger.Printf(msg, v...)
+// jsonRecord is the structure written to the sinks when JSON is enabled. Fields
+// is omitted unless the caller has structured key/value pairs to attach, e.g.
+// slog attributes, so plain messages don't grow an empty "fields": {}
+type jsonRecord struct {
+	Time    string            `json:"time"`
+	Level   string            `json:"level"`
+	File    string            `json:"file"`
+	Line    int               `json:"line"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// formatJSON renders a log line as a single-line JSON object. It is used
+// instead of formatText when JSON is set to true. fields, if non-nil, is
+// emitted as its own "fields" object instead of being folded into message
+func formatJSON(lvl, fn string, line int, message string, fields map[string]string) string {
+	b, err := json.Marshal(jsonRecord{
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Level:   lvl,
+		File:    fn,
+		Line:    line,
+		Message: message,
+		Fields:  fields,
+	})
+	if err != nil {
+		return err.Error()
 	}
+	return string(b)
 }
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFatalReportsCallerLocation re-executes this test binary as a subprocess
+// with helperFatalEnv set, so Fatal's os.Exit(1) doesn't kill the real test
+// run. It asserts the printed location is this file, not log.go, where
+// Fatal's implementation lives
+func TestFatalReportsCallerLocation(t *testing.T) {
+	if os.Getenv(helperFatalEnv) == "1" {
+		Fatal("boom") // the call site we expect to see reported below
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestFatalReportsCallerLocation")
+	cmd.Env = append(os.Environ(), helperFatalEnv+"=1")
+	out, err := cmd.CombinedOutput()
+
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected the subprocess to exit with code 1, got err=%v output=%s", err, out)
+	}
+	if !strings.Contains(string(out), "log_test.go") {
+		t.Fatalf("expected the caller's file:line to be reported, got %s", out)
+	}
+	if strings.Contains(string(out), "log.go") {
+		t.Fatalf("expected Fatal's own frame not to leak into the output, got %s", out)
+	}
+}
+
+// TestFatalBypassesRateLimitAndDedup re-executes this test binary as a
+// subprocess with rate limiting configured to deny every call and dedup
+// configured to suppress everything, then sends Fatal a message. Both layers
+// would otherwise swallow it, leaving the process to exit having logged
+// nothing
+func TestFatalBypassesRateLimitAndDedup(t *testing.T) {
+	if os.Getenv(helperFatalGatesEnv) == "1" {
+		SetRateLimit(1, 0) // no burst at all: every rateLimitAllow() call fails
+		SetDedupWindow(time.Hour)
+		Fatal("boom")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestFatalBypassesRateLimitAndDedup")
+	cmd.Env = append(os.Environ(), helperFatalGatesEnv+"=1")
+	out, err := cmd.CombinedOutput()
+
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected the subprocess to exit with code 1, got err=%v output=%s", err, out)
+	}
+	if !strings.Contains(string(out), "boom") {
+		t.Fatalf("expected Fatal's message to be printed despite rate limiting and dedup, got %s", out)
+	}
+}
+
+const helperFatalEnv = "LOG_FATAL_HELPER"
+const helperFatalGatesEnv = "LOG_FATAL_GATES_HELPER"
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Rate limiting state. Disabled by default
+var rateLimitMu sync.Mutex
+var rateLimitPerSecond int
+var rateLimitBurst int
+var rateLimitTokens float64
+var rateLimitLast time.Time
+
+// SetRateLimit limits this package to printing at most perSecond messages per
+// second across all levels combined, with bursts of up to burst messages
+// allowed. Messages logged in excess of the rate are dropped silently. The
+// stack frames printed after an error, Fatal's output and dedup "repeated N
+// times" summaries are not counted against the limit. A perSecond of 0
+// disables rate limiting, which is the default
+func SetRateLimit(perSecond int, burst int) {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+	rateLimitPerSecond = perSecond
+	rateLimitBurst = burst
+	rateLimitTokens = float64(burst)
+	rateLimitLast = time.Now()
+}
+
+// rateLimitAllow reports whether the rate limiter currently has a token
+// available and consumes one if so. It always reports true when rate
+// limiting is disabled
+func rateLimitAllow() bool {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	if rateLimitPerSecond <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	rateLimitTokens += now.Sub(rateLimitLast).Seconds() * float64(rateLimitPerSecond)
+	if rateLimitTokens > float64(rateLimitBurst) {
+		rateLimitTokens = float64(rateLimitBurst)
+	}
+	rateLimitLast = now
+
+	if rateLimitTokens < 1 {
+		return false
+	}
+
+	rateLimitTokens--
+	return true
+}
+
+// Deduplication state. Disabled by default
+var dedupMu sync.Mutex
+var dedupWindow time.Duration
+var dedupEntries = map[dedupKey]*dedupEntry{}
+var dedupFlusherOnce sync.Once
+
+// dedupKey identifies a class of messages which are considered duplicates of
+// each other: the same message, logged from the same level and source
+// location
+type dedupKey struct {
+	level int
+	file  string
+	line  int
+}
+
+type dedupEntry struct {
+	colour  string
+	lvl     string
+	message string
+	first   time.Time
+	count   int
+}
+
+// SetDedupWindow enables suppression of duplicate log messages. When the
+// exact same message is logged from the same level, file and line more than
+// once within d, the repeats are suppressed and replaced with a single
+// "message (repeated N times)" summary once the window closes. This solves
+// the problem described in the SetDefaultLevel doc comment about a verbose
+// http logger spamming the logs, without having to demote its entire level.
+// A window of 0 disables deduplication, which is the default
+func SetDedupWindow(d time.Duration) {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+	dedupWindow = d
+
+	if d > 0 {
+		dedupFlusherOnce.Do(func() { go dedupFlusher() })
+	}
+}
+
+// dedupAllow reports whether msg should be printed immediately. As a side
+// effect it may flush and print a "repeated N times" summary for a message
+// which just fell out of the dedup window
+func dedupAllow(level int, colour, lvl, file string, line int, msg string) bool {
+	dedupMu.Lock()
+
+	if dedupWindow <= 0 {
+		dedupMu.Unlock()
+		return true
+	}
+
+	key := dedupKey{level, file, line}
+	now := time.Now()
+
+	e, ok := dedupEntries[key]
+	if ok && e.message == msg && now.Sub(e.first) < dedupWindow {
+		e.count++
+		dedupMu.Unlock()
+		return false
+	}
+
+	dedupEntries[key] = &dedupEntry{colour: colour, lvl: lvl, message: msg, first: now, count: 1}
+	dedupMu.Unlock()
+
+	if ok && e.count > 1 {
+		emitRepeated(key, e)
+	}
+
+	return true
+}
+
+// dedupFlusher periodically emits "repeated N times" summaries for entries
+// whose window has closed without a new occurrence resetting them, so spam
+// which simply stops still gets its summary printed
+func dedupFlusher() {
+	ticker := time.NewTicker(time.Second)
+	for range ticker.C {
+		dedupMu.Lock()
+		window := dedupWindow
+		now := time.Now()
+		for key, e := range dedupEntries {
+			if e.count > 1 && now.Sub(e.first) >= window {
+				delete(dedupEntries, key)
+				dedupMu.Unlock()
+				emitRepeated(key, e)
+				dedupMu.Lock()
+			}
+		}
+		dedupMu.Unlock()
+	}
+}
+
+// emitRepeated prints the "repeated N times" summary for a dedup entry
+// through the normal formatting and sink pipeline. e.count includes the
+// first, already-printed occurrence, so the summary reports e.count-1
+// suppressed repeats
+func emitRepeated(key dedupKey, e *dedupEntry) {
+	msg := fmt.Sprintf("%s (repeated %d times)", e.message, e.count-1)
+
+	var formatted string
+	if JSON {
+		formatted = formatJSON(e.lvl, key.file, key.line, msg, nil)
+	} else {
+		formatted = formatText(e.colour, e.lvl, key.file, key.line, msg)
+	}
+
+	writeSinks(key.level, key.file, key.line, formatted)
+}
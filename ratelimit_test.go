@@ -0,0 +1,108 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitAllow(t *testing.T) {
+	SetRateLimit(2, 2)
+	defer SetRateLimit(0, 0)
+
+	if !rateLimitAllow() || !rateLimitAllow() {
+		t.Fatal("expected a burst of 2 to be allowed immediately")
+	}
+	if rateLimitAllow() {
+		t.Fatal("expected a third immediate call to be rate limited")
+	}
+}
+
+func TestDedupRunsBeforeRateLimit(t *testing.T) {
+	SetDedupWindow(time.Hour)
+	SetRateLimit(1, 2)
+	defer SetDedupWindow(0)
+	defer SetRateLimit(0, 0)
+	defer func() { dedupEntries = map[dedupKey]*dedupEntry{} }()
+
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	// Spend one of the rate limiter's two tokens on a message that will then
+	// be suppressed as a duplicate every following call
+	printAt(LevelInfo, "92", "INF", "ratelimit_test.go", 300, "spam", "", nil, true, true)
+	for i := 0; i < 5; i++ {
+		printAt(LevelInfo, "92", "INF", "ratelimit_test.go", 300, "spam", "", nil, true, true)
+	}
+
+	// Dedup must run before the rate limiter, so none of the suppressed
+	// duplicates above should have consumed a token, leaving one available
+	// for an unrelated message
+	printAt(LevelInfo, "92", "INF", "ratelimit_test.go", 301, "unrelated", "", nil, true, true)
+
+	found := false
+	for _, l := range c.lines {
+		if strings.Contains(l, "unrelated") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected the unrelated message to still have a token available, got %v", c.lines)
+	}
+}
+
+func TestRateLimitDisabledByDefault(t *testing.T) {
+	SetRateLimit(0, 0)
+	for i := 0; i < 100; i++ {
+		if !rateLimitAllow() {
+			t.Fatal("expected rate limiting to be disabled when perSecond is 0")
+		}
+	}
+}
+
+func TestDedupSuppressesDuplicates(t *testing.T) {
+	SetDedupWindow(time.Hour)
+	defer SetDedupWindow(0)
+	defer func() { dedupEntries = map[dedupKey]*dedupEntry{} }()
+
+	if !dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 100, "spam") {
+		t.Fatal("first occurrence should always be printed")
+	}
+	if dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 100, "spam") {
+		t.Fatal("a duplicate within the window should be suppressed")
+	}
+	if !dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 100, "a different message") {
+		t.Fatal("a different message at the same location should not be suppressed")
+	}
+}
+
+func TestDedupEmitsRepeatedSummary(t *testing.T) {
+	SetDedupWindow(10 * time.Millisecond)
+	defer SetDedupWindow(0)
+	defer func() { dedupEntries = map[dedupKey]*dedupEntry{} }()
+
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 200, "spam")
+	dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 200, "spam")
+	dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 200, "spam")
+
+	time.Sleep(20 * time.Millisecond)
+
+	// The window has now closed. The next occurrence at the same key flushes
+	// a "repeated N times" summary for the previous entry before continuing
+	dedupAllow(LevelInfo, "92", "INF", "dedup_test.go", 200, "spam")
+
+	found := false
+	for _, l := range c.lines {
+		if strings.Contains(l, "repeated 2 times") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a 'repeated 2 times' summary, got %v", c.lines)
+	}
+}
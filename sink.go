@@ -0,0 +1,212 @@
+package log
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Sink is the interface implemented by log output destinations. A Sink
+// receives every log line after level filtering and formatting (Colours,
+// JSON) have already been applied, and is responsible for delivering it to
+// wherever it sends logs
+type Sink interface {
+	// Write receives a single log line. level is the level the line was
+	// logged at (one of the Level* constants), file and line are the source
+	// location the line originated from, and msg is the fully formatted
+	// message
+	Write(level int, file string, line int, msg string)
+}
+
+var sinksMu sync.RWMutex
+var sinks = []Sink{StdoutSink}
+
+// AddSink registers a Sink which receives a copy of every log line from this
+// point onward. Sinks are called in the order they were added
+func AddSink(s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks = append(sinks, s)
+}
+
+// RemoveSink unregisters a previously added Sink. It is a no-op if the sink
+// was never registered. The default stdout sink can be removed this way too,
+// for example to replace it with a JSON file sink
+func RemoveSink(s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	for i, sink := range sinks {
+		if sink == s {
+			sinks = append(sinks[:i], sinks[i+1:]...)
+			return
+		}
+	}
+}
+
+// writeSinks delivers a formatted log line to every registered sink
+func writeSinks(level int, file string, line int, msg string) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, s := range sinks {
+		s.Write(level, file, line, msg)
+	}
+}
+
+// flusher is an optional interface a Sink can implement if it buffers data
+// and needs to flush it before the process exits
+type flusher interface {
+	Flush() error
+}
+
+// flushSinks flushes every registered sink which implements flusher. It is
+// called by Fatal before exiting
+func flushSinks() {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, s := range sinks {
+		if f, ok := s.(flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// stdOutSink writes every log line to stdout. It is registered by default and
+// replicates the behaviour this package had before Sinks were introduced
+type stdOutSink struct{ logger *log.Logger }
+
+func (s *stdOutSink) Write(level int, file string, line int, msg string) {
+	s.logger.Println(msg)
+}
+
+// StdoutSink is the default sink, registered at startup. Pass it to
+// RemoveSink to stop logging to stdout
+var StdoutSink Sink = &stdOutSink{logger: log.New(os.Stdout, "", log.LUTC)}
+
+// StderrSink writes log lines at LevelWarning and above to stderr, in
+// addition to wherever they are already being sent. This is useful for
+// daemons whose process supervisor only captures stderr, or which want
+// warnings and errors to stand out from the rest of the output
+type StderrSink struct{ logger *log.Logger }
+
+// NewStderrSink creates a Sink which duplicates warnings and errors to
+// stderr
+func NewStderrSink() *StderrSink {
+	return &StderrSink{logger: log.New(os.Stderr, "", log.LUTC)}
+}
+
+func (s *StderrSink) Write(level int, file string, line int, msg string) {
+	if level > LevelWarning {
+		return
+	}
+	s.logger.Println(msg)
+}
+
+// FileSink writes log lines to a file on disk, rotating it once it reaches
+// MaxBytes. Up to MaxBackups old files are kept around, numbered by
+// appending .1, .2, etc. to Path, with .1 being the most recent
+type FileSink struct {
+	Path       string
+	MaxBytes   int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens (or creates) the log file at path and returns a FileSink
+// which rotates it once it reaches maxBytes, keeping maxBackups old files
+// around. A maxBytes of 0 disables rotation
+func NewFileSink(path string, maxBytes int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{Path: path, MaxBytes: maxBytes, MaxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// Write implements Sink. Write errors are ignored, since there usually isn't
+// a reasonable way to act on them from inside a log call
+func (s *FileSink) Write(level int, file string, line int, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return
+	}
+
+	n, err := fmt.Fprintln(s.file, msg)
+	if err != nil {
+		return
+	}
+	s.size += int64(n)
+
+	if s.MaxBytes > 0 && s.size >= s.MaxBytes {
+		s.rotate()
+	}
+}
+
+// Flush syncs the underlying file to disk
+func (s *FileSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+// rotate closes the current log file, shifts the existing backups up by one
+// and opens a fresh file in their place. If MaxBackups is 0 no backups are
+// kept and the current file is simply discarded instead of shifted. The
+// caller must hold s.mu
+func (s *FileSink) rotate() {
+	s.file.Close()
+	s.file = nil
+
+	if s.MaxBackups <= 0 {
+		os.Remove(s.Path)
+	} else {
+		for i := s.MaxBackups; i > 0; i-- {
+			if i == s.MaxBackups {
+				os.Remove(s.backupPath(i))
+			}
+			os.Rename(s.backupPath(i-1), s.backupPath(i))
+		}
+	}
+
+	if err := s.open(); err != nil {
+		// Can't go through Error here: rotate runs with s.mu held, and a
+		// registered FileSink would call back into Write -> s.mu.Lock() and
+		// deadlock. Write straight to stderr instead
+		fmt.Fprintf(os.Stderr, "Unable to reopen log file %s after rotation: %v\n", s.Path, err)
+	}
+}
+
+// backupPath returns Path itself for n == 0, or Path with ".n" appended for
+// n > 0
+func (s *FileSink) backupPath(n int) string {
+	if n == 0 {
+		return s.Path
+	}
+	return fmt.Sprintf("%s.%d", s.Path, n)
+}
@@ -0,0 +1,68 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// SyslogSink forwards log lines to the local journald instance when one is
+// available, or to the local syslog daemon otherwise, preserving the log
+// level as the record's priority instead of flattening it into stdout text
+type SyslogSink struct {
+	useJournal bool
+	writer     *syslog.Writer
+}
+
+// NewSyslogSink connects to the local systemd journal, falling back to the
+// classic syslog daemon if journald isn't running. tag is used as the
+// syslog identifier when falling back
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	if journal.Enabled() {
+		return &SyslogSink{useJournal: true}, nil
+	}
+
+	w, err := syslog.New(syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{writer: w}, nil
+}
+
+func (s *SyslogSink) Write(level int, file string, line int, msg string) {
+	if s.useJournal {
+		journal.Send(msg, journalPriority(level), map[string]string{
+			"CODE_FILE": file,
+			"CODE_LINE": fmt.Sprintf("%d", line),
+		})
+		return
+	}
+
+	switch level {
+	case LevelError:
+		s.writer.Err(msg)
+	case LevelWarning:
+		s.writer.Warning(msg)
+	case LevelInfo:
+		s.writer.Info(msg)
+	default:
+		s.writer.Debug(msg)
+	}
+}
+
+// journalPriority maps this package's log levels onto journald priorities
+func journalPriority(level int) journal.Priority {
+	switch level {
+	case LevelError:
+		return journal.PriErr
+	case LevelWarning:
+		return journal.PriWarning
+	case LevelInfo:
+		return journal.PriInfo
+	default:
+		return journal.PriDebug
+	}
+}
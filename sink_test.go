@@ -0,0 +1,65 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// captureSink is a Sink which records every line it receives, for use by
+// tests across this package
+type captureSink struct{ lines []string }
+
+func (c *captureSink) Write(level int, file string, line int, msg string) {
+	c.lines = append(c.lines, msg)
+}
+
+func TestFileSinkRotatesAtMaxBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	s, err := NewFileSink(path, 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		s.Write(LevelInfo, "f", 1, "0123456789")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= 100 {
+		t.Fatalf("expected file to have rotated, got size %d", info.Size())
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected a .1 backup to exist: %v", err)
+	}
+}
+
+func TestFileSinkMaxBackupsZeroTruncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	s, err := NewFileSink(path, 50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		s.Write(LevelInfo, "f", 1, "0123456789")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= 100 {
+		t.Fatalf("expected rotation to truncate the file even with no backups, got size %d", info.Size())
+	}
+
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatal("expected no backup file to be created when MaxBackups is 0")
+	}
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+// SlogHandler is a slog.Handler implementation which forwards slog records
+// into this package, so this package can be used as the backend for Go's
+// standard log/slog package. Construct one with NewSlogHandler and pass it to
+// slog.New
+type SlogHandler struct {
+	groups []string
+	attrs  []slogAttr
+}
+
+// slogAttr is a resolved slog attribute, kept in both its human-readable
+// key=value form (for the default text output) and as a separate key/value
+// pair (for JSON output, where each attribute becomes its own field)
+type slogAttr struct {
+	text  string
+	key   string
+	value string
+}
+
+// NewSlogHandler creates a slog.Handler which forwards records to this
+// package's log levels and print pipeline. Use it like:
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler()))
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{}
+}
+
+// Enabled reports whether the handler handles records at the given level,
+// based on the level set with SetLogLevel
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogLevel(level) <= logLevel
+}
+
+// Handle formats the record's message and attributes and passes them through
+// this package's regular print pipeline, so the record respects Colours, JSON
+// and any other output configuration. The record's file and line are taken
+// from r.PC, which slog captures at the original call site, rather than from
+// this function's own position in the call stack
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slogAttr, 0, len(h.attrs)+r.NumAttrs())
+	attrs = append(attrs, h.attrs...)
+
+	r.Attrs(func(a slog.Attr) bool {
+		if attr, ok := resolveAttr(h.groups, a); ok {
+			attrs = append(attrs, attr)
+		}
+		return true
+	})
+
+	msg := r.Message
+
+	var suffix string
+	var fields map[string]string
+	if len(attrs) > 0 {
+		texts := make([]string, len(attrs))
+		fields = make(map[string]string, len(attrs))
+		for i, a := range attrs {
+			texts[i] = a.text
+			fields[a.key] = a.value
+		}
+		suffix = strings.Join(texts, " ")
+	}
+
+	var fn string
+	var line int
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fn, line = frame.File, frame.Line
+	}
+
+	var colour, lvl string
+	switch {
+	case r.Level >= slog.LevelError:
+		colour, lvl = "91", "ERR"
+	case r.Level >= slog.LevelWarn:
+		colour, lvl = "93", "WRN"
+	case r.Level >= slog.LevelInfo:
+		colour, lvl = "92", "INF"
+	case r.Level >= slog.LevelDebug:
+		colour, lvl = "96", "DBG"
+	default:
+		colour, lvl = "95", "TRC"
+	}
+
+	printAt(slogLevel(r.Level), colour, lvl, fn, line, msg, suffix, fields, true, true)
+
+	return nil
+}
+
+// WithAttrs returns a new handler which always logs the given attributes in
+// addition to the ones passed to Handle
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	nh := &SlogHandler{
+		groups: h.groups,
+		attrs:  make([]slogAttr, len(h.attrs), len(h.attrs)+len(attrs)),
+	}
+	copy(nh.attrs, h.attrs)
+
+	for _, a := range attrs {
+		if attr, ok := resolveAttr(h.groups, a); ok {
+			nh.attrs = append(nh.attrs, attr)
+		}
+	}
+
+	return nh
+}
+
+// WithGroup returns a new handler which prefixes the keys of all attributes
+// added after this call with the given group name
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	nh := &SlogHandler{
+		attrs:  h.attrs,
+		groups: make([]string, len(h.groups)+1),
+	}
+	copy(nh.groups, h.groups)
+	nh.groups[len(h.groups)] = name
+	return nh
+}
+
+// slogLevel maps a slog.Level onto this package's log levels
+func slogLevel(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return LevelError
+	case l >= slog.LevelWarn:
+		return LevelWarning
+	case l >= slog.LevelInfo:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+// resolveAttr resolves a slog attribute and renders it both as a key=value
+// pair for the human-readable format and as a separate key/value pair for
+// JSON output, prefixing the key with any active groups. Empty attributes
+// (from slog.Group with no attributes) are skipped, reported via ok
+func resolveAttr(groups []string, a slog.Attr) (attr slogAttr, ok bool) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) || (a.Value.Kind() == slog.KindGroup && len(a.Value.Group()) == 0) {
+		return slogAttr{}, false
+	}
+
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+	value := a.Value.String()
+
+	return slogAttr{text: key + "=" + value, key: key, value: value}, true
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandlerReportsCallerLocation(t *testing.T) {
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	l := slog.New(NewSlogHandler())
+	l.Info("hello") // the call site we expect to see reported below
+
+	if len(c.lines) == 0 {
+		t.Fatal("expected a line to be logged")
+	}
+	if !strings.Contains(c.lines[0], "slog_test.go") {
+		t.Fatalf("expected the real call site to be reported, got %q", c.lines[0])
+	}
+	if strings.Contains(c.lines[0], "slog.go") {
+		t.Fatalf("expected SlogHandler.Handle's own frame not to leak into the output, got %q", c.lines[0])
+	}
+}
+
+func TestSlogHandlerWithAttrsAndGroup(t *testing.T) {
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	h := NewSlogHandler().
+		WithAttrs([]slog.Attr{slog.String("service", "api")}).
+		WithGroup("req").
+		WithAttrs([]slog.Attr{slog.Int("id", 42)})
+
+	slog.New(h).Warn("boom")
+
+	if len(c.lines) == 0 {
+		t.Fatal("expected a line to be logged")
+	}
+	line := c.lines[0]
+	if !strings.Contains(line, "service=api") {
+		t.Fatalf("expected the ungrouped attribute to appear, got %q", line)
+	}
+	if !strings.Contains(line, "req.id=42") {
+		t.Fatalf("expected the grouped attribute to be prefixed with its group, got %q", line)
+	}
+}
+
+func TestSlogHandlerJSONEmitsStructuredFields(t *testing.T) {
+	JSON = true
+	defer func() { JSON = false }()
+
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	h := NewSlogHandler().
+		WithAttrs([]slog.Attr{slog.String("service", "api")}).
+		WithGroup("req").
+		WithAttrs([]slog.Attr{slog.Int("id", 42)})
+
+	slog.New(h).Warn("boom")
+
+	if len(c.lines) == 0 {
+		t.Fatal("expected a line to be logged")
+	}
+
+	var rec jsonRecord
+	if err := json.Unmarshal([]byte(c.lines[0]), &rec); err != nil {
+		t.Fatalf("expected valid JSON, got %q: %v", c.lines[0], err)
+	}
+	if rec.Message != "boom" {
+		t.Fatalf("expected the message field to stay plain, got %q", rec.Message)
+	}
+	if rec.Fields["service"] != "api" {
+		t.Fatalf("expected the ungrouped attribute as a structured field, got %v", rec.Fields)
+	}
+	if rec.Fields["req.id"] != "42" {
+		t.Fatalf("expected the grouped attribute as a structured field, got %v", rec.Fields)
+	}
+}
+
+func TestSlogHandlerEnabledRespectsLogLevel(t *testing.T) {
+	old := logLevel
+	defer func() { logLevel = old }()
+	logLevel = LevelWarning
+
+	h := NewSlogHandler()
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("expected Info to be disabled when logLevel is Warning")
+	}
+	if !h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("expected Error to be enabled when logLevel is Warning")
+	}
+}
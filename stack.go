@@ -0,0 +1,78 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+)
+
+// stackDepth is the maximum number of stack frames captured by Error. It can
+// be changed with SetStackDepth
+var stackDepth = 32
+
+// SetStackDepth sets the maximum number of stack frames Error captures and
+// prints. The default is 32
+func SetStackDepth(n int) {
+	stackDepth = n
+}
+
+// logStack captures the stack of the function which called the Error logging
+// the message, and prints each frame through the normal print pipeline, so it
+// respects Colours, sinks and JSON just like the rest of the package. Each
+// frame is reported under its own file and line, via printAt, rather than the
+// file and line of this logStack call. Frames are exempt from rate limiting
+// so a burst of unrelated log traffic can't truncate the trace mid-frame.
+// deduped controls whether the frames are still subject to deduplication;
+// Fatal passes false so its final stack trace is never suppressed
+func logStack(level int, deduped bool) {
+	for _, frame := range stack(2) {
+		printAt(level, "91", "ERR", frame.File, frame.Line, "  "+frame.Function, "", nil, deduped, false)
+	}
+}
+
+// stack captures the current goroutine's call stack up to stackDepth frames,
+// skipping the given number of frames in addition to runtime.Callers and
+// stack() itself (so a skip of 0 starts at stack's caller), and returns the
+// resulting frames. Frames belonging to the Go runtime and
+// standard library are filtered out by default
+func stack(skip int) []runtime.Frame {
+	pc := make([]uintptr, stackDepth)
+	n := runtime.Callers(skip+2, pc)
+	if n == 0 {
+		return nil
+	}
+
+	frames := runtime.CallersFrames(pc[:n])
+
+	var result []runtime.Frame
+	for {
+		frame, more := frames.Next()
+
+		if !isRuntimeFrame(frame) {
+			result = append(result, frame)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return result
+}
+
+// isRuntimeFrame reports whether frame belongs to the Go runtime or the
+// standard library, rather than application code
+func isRuntimeFrame(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, "runtime.") ||
+		strings.HasPrefix(frame.Function, "syscall.") {
+		return true
+	}
+
+	// runtime.GOROOT() returns "" for trimpath'd or relocated binaries, which
+	// are common in production; an empty prefix would otherwise match every
+	// file and classify the whole stack as runtime
+	if goroot := runtime.GOROOT(); goroot != "" && strings.HasPrefix(frame.File, goroot) {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIsRuntimeFrameFiltersStdlib(t *testing.T) {
+	pc := make([]uintptr, 10)
+	n := runtime.Callers(0, pc)
+	frame, _ := runtime.CallersFrames(pc[:n]).Next() // the runtime.Callers call itself
+
+	if !isRuntimeFrame(frame) {
+		t.Fatalf("expected %s to be classified as a runtime frame", frame.Function)
+	}
+}
+
+func TestIsRuntimeFrameKeepsApplicationFrames(t *testing.T) {
+	// An application frame outside GOROOT must not be classified as a runtime
+	// frame. This runs with the process's real GOROOT, so the guard for an
+	// empty GOROOT in isRuntimeFrame is not exercised here
+	frame := runtime.Frame{Function: "example.com/app.doWork", File: "/app/main.go", Line: 42}
+	if isRuntimeFrame(frame) {
+		t.Fatalf("expected application frame %s not to be classified as a runtime frame", frame.Function)
+	}
+}
+
+func stackTestCallSite() []runtime.Frame {
+	return stack(1)
+}
+
+func TestStackReportsCallerNotItself(t *testing.T) {
+	frames := stackTestCallSite()
+	if len(frames) == 0 {
+		t.Fatal("expected at least one frame")
+	}
+
+	if !strings.Contains(frames[0].Function, "TestStackReportsCallerNotItself") {
+		t.Fatalf("expected the first frame to be the caller of stackTestCallSite, got %s", frames[0].Function)
+	}
+}
+
+func logStackTestCallSite() {
+	logStack(LevelError, true)
+}
+
+func TestLogStackReportsEachFramesOwnLocation(t *testing.T) {
+	c := &captureSink{}
+	AddSink(c)
+	defer RemoveSink(c)
+
+	logStackTestCallSite()
+
+	if len(c.lines) == 0 {
+		t.Fatal("expected logStack to print at least one frame")
+	}
+	if !strings.Contains(c.lines[0], "stack_test.go") {
+		t.Fatalf("expected the frame to be reported under its own file, got %q", c.lines[0])
+	}
+}